@@ -11,7 +11,13 @@ import (
 	"os"
 	"path"
 
-	"gopkg.in/yaml.v2"
+	// This package was moved from yaml.v2 to yaml.v3 so that StringList can
+	// implement UnmarshalYAML(*yaml.Node) and read a field's "!override"/
+	// "!extend" tag, which v2's unmarshal-callback API does not expose. The
+	// struct `yaml:"..."` tags are unaffected, but v3 differs from v2 in a
+	// few edge cases (map key ordering, anchor/merge handling) worth knowing
+	// about when diagnosing a config.yml that now parses differently.
+	"gopkg.in/yaml.v3"
 )
 
 const (
@@ -32,17 +38,24 @@ type Swarm struct {
 	Instances []Instance `yaml:"instances"`
 }
 
+// Instance describes a single account in a Swarm. The Features, Compat and
+// SuspicionAvoidance pointers are optional; a nil pointer means the instance
+// inherits the root config's value for that section. Use Config.EffectiveFor
+// to resolve an instance's fully merged configuration.
 type Instance struct {
-	Token     string  `yaml:"token"`
-	ChannelID string  `yaml:"channel_id"`
-	Shifts    []Shift `yaml:"shifts"`
+	Token              string              `yaml:"token"`
+	ChannelID          string              `yaml:"channel_id"`
+	Shifts             []Shift             `yaml:"shifts"`
+	Features           *Features           `yaml:"features"`
+	Compat             *Compat             `yaml:"compatibility"`
+	SuspicionAvoidance *SuspicionAvoidance `yaml:"suspicion_avoidance"`
 }
 
 type Compat struct {
-	PostmemeOpts    []string `yaml:"postmeme_options"`
-	AllowedSearches []string `yaml:"allowed_searches"`
-	Cooldown        Cooldown `yaml:"cooldown"`
-	AutoSell        []string `yaml:"auto_sell"`
+	PostmemeOpts    StringList `yaml:"postmeme_options"`
+	AllowedSearches StringList `yaml:"allowed_searches"`
+	Cooldown        Cooldown   `yaml:"cooldown"`
+	AutoSell        StringList `yaml:"auto_sell"`
 }
 
 type Cooldown struct {
@@ -61,6 +74,12 @@ type Features struct {
 	BalanceCheck bool     `yaml:"balance_check"`
 	LogToFile    bool     `yaml:"log_to_file"`
 	Debug        bool     `yaml:"debug"`
+
+	// AllowUnsafeReload permits Watcher to hot-apply config changes that
+	// normally require a restart (token, channel_id, swarm.instances
+	// topology). Without it, such changes are rejected and the watcher
+	// keeps running on the last-good config.
+	AllowUnsafeReload bool `yaml:"allow_unsafe_reload"`
 }
 
 type AutoBuy struct {
@@ -75,6 +94,9 @@ type Commands struct {
 }
 
 type SuspicionAvoidance struct {
+	// Timezone is the IANA timezone name used to evaluate wall-clock shifts
+	// (the "at" form below). Defaults to UTC when empty.
+	Timezone     string       `yaml:"timezone"`
 	Typing       Typing       `yaml:"typing"`
 	MessageDelay MessageDelay `yaml:"message_delay"`
 	Shifts       []Shift      `yaml:"shifts"`
@@ -93,9 +115,26 @@ type MessageDelay struct {
 }
 
 // Shift indicates an application state (active or dormant) for a duration.
+//
+// A shift takes one of two mutually exclusive forms:
+//   - duration-based: Duration is set and the shift runs back-to-back with
+//     the other shifts in the cycle.
+//   - wall-clock-based: At is set to a "HH:MM-HH:MM" window and the shift is
+//     active whenever the current time in SuspicionAvoidance.Timezone falls
+//     inside that window on one of Days (all days if Days is empty).
+//
+// Mixing both forms on the same entry is rejected by Validate.
 type Shift struct {
 	State    string   `yaml:"state"`
 	Duration Duration `yaml:"duration"`
+	At       string   `yaml:"at"`
+	Days     []string `yaml:"days"`
+}
+
+// isWallClock reports whether the shift uses the "at" window form rather
+// than the duration-based cycle form.
+func (s Shift) isWallClock() bool {
+	return s.At != ""
 }
 
 // Duration is not related to a time.Duration. It is a structure used in a Shift
@@ -120,47 +159,66 @@ func Load(dir string) (Config, error) {
 	return cfg, nil
 }
 
+// Validate checks that c is complete and internally consistent, including
+// the effective, merged config of every swarm instance.
 func (c Config) Validate() error {
+	return c.validate("")
+}
+
+// validate is the implementation behind Validate. prefix is prepended to
+// every error path so that errors raised while validating an instance's
+// effective config (see EffectiveFor) read as e.g.
+// "swarm.instances[2].compatibility.cooldown.beg: ...".
+func (c Config) validate(prefix string) error {
 	if c.Token == "" {
-		return fmt.Errorf("token: no authorization token")
+		return fmt.Errorf("%stoken: no authorization token", prefix)
 	}
 	if c.ChannelID == "" {
-		return fmt.Errorf("channel_id: no channel id")
+		return fmt.Errorf("%schannel_id: no channel id", prefix)
 	}
 	if len(c.SuspicionAvoidance.Shifts) == 0 {
-		return fmt.Errorf("suspicion_avoidance.shifts: no shifts, at least 1 is required")
+		return fmt.Errorf("%ssuspicion_avoidance.shifts: no shifts, at least 1 is required", prefix)
 	}
-	if len(c.Compat.PostmemeOpts) == 0 {
-		return fmt.Errorf("compatibility.postmeme: no compatibility options")
+	if len(c.Compat.PostmemeOpts.Values) == 0 {
+		return fmt.Errorf("%scompatibility.postmeme: no compatibility options", prefix)
 	}
-	if len(c.Compat.AllowedSearches) == 0 {
-		return fmt.Errorf("compatibility.allowed_searches: no compatibility options")
+	if len(c.Compat.AllowedSearches.Values) == 0 {
+		return fmt.Errorf("%scompatibility.allowed_searches: no compatibility options", prefix)
 	}
 	if c.Compat.Cooldown.Postmeme <= 0 {
-		return fmt.Errorf("compatibility.cooldown.postmeme: value must be greater than 0")
+		return fmt.Errorf("%scompatibility.cooldown.postmeme: value must be greater than 0", prefix)
 	}
 	if c.Compat.Cooldown.Hunt <= 0 {
-		return fmt.Errorf("compatibility.cooldown.hunt: value must be greater than 0")
+		return fmt.Errorf("%scompatibility.cooldown.hunt: value must be greater than 0", prefix)
 	}
 	if c.Compat.Cooldown.Highlow <= 0 {
-		return fmt.Errorf("compatibility.cooldown.highlow: value must be greater than 0")
+		return fmt.Errorf("%scompatibility.cooldown.highlow: value must be greater than 0", prefix)
 	}
 	if c.Compat.Cooldown.Fish <= 0 {
-		return fmt.Errorf("compatibility.cooldown.fish: value must be greater than 0")
+		return fmt.Errorf("%scompatibility.cooldown.fish: value must be greater than 0", prefix)
 	}
 	if c.Compat.Cooldown.Search <= 0 {
-		return fmt.Errorf("compatibility.cooldown.search: value must be greater than 0")
+		return fmt.Errorf("%scompatibility.cooldown.search: value must be greater than 0", prefix)
 	}
 	if c.Compat.Cooldown.Beg <= 0 {
-		return fmt.Errorf("compatibility.cooldown.beg: value must be greater than 0")
+		return fmt.Errorf("%scompatibility.cooldown.beg: value must be greater than 0", prefix)
 	}
 	if c.Compat.Cooldown.Margin < 0 {
-		return fmt.Errorf("compatibility.cooldown.margin: value must be greater than or equal to 0")
+		return fmt.Errorf("%scompatibility.cooldown.margin: value must be greater than or equal to 0", prefix)
 	}
 
 	for _, shift := range c.SuspicionAvoidance.Shifts {
 		if shift.State != ShiftStateActive && shift.State != ShiftStateDormant {
-			return fmt.Errorf("invalid shift state: %v", shift.State)
+			return fmt.Errorf("%sinvalid shift state: %v", prefix, shift.State)
+		}
+	}
+	if err := validateShifts(c.SuspicionAvoidance.Shifts, c.SuspicionAvoidance.Timezone); err != nil {
+		return fmt.Errorf("%ssuspicion_avoidance: %v", prefix, err)
+	}
+
+	for i := range c.Swarm.Instances {
+		if _, err := c.EffectiveFor(i); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -0,0 +1,107 @@
+// Copyright (C) 2021 The Dank Grinder authors.
+//
+// This source code has been released under the GNU Affero General Public
+// License v3.0. A copy of this license is available at
+// https://www.gnu.org/licenses/agpl-3.0.en.html
+
+package config
+
+import "fmt"
+
+// EffectiveFor returns the fully merged and validated Config for the i-th
+// swarm instance. Sections an instance leaves nil (Features, Compat,
+// SuspicionAvoidance) are inherited from the root config; sections it sets
+// replace the root's, except within Compat: its StringList fields extend the
+// root's list unless tagged "!override" (see StringList), and its Cooldown is
+// merged field by field (see mergeCooldown).
+func (c Config) EffectiveFor(i int) (Config, error) {
+	if i < 0 || i >= len(c.Swarm.Instances) {
+		return Config{}, fmt.Errorf("swarm.instances[%d]: index out of range", i)
+	}
+	inst := c.Swarm.Instances[i]
+
+	eff := c
+	eff.Token = inst.Token
+	eff.ChannelID = inst.ChannelID
+	eff.Swarm = Swarm{}
+
+	if inst.Features != nil {
+		eff.Features = *inst.Features
+	}
+	if inst.Compat != nil {
+		eff.Compat = mergeCompat(c.Compat, *inst.Compat)
+	}
+	if inst.SuspicionAvoidance != nil {
+		eff.SuspicionAvoidance = *inst.SuspicionAvoidance
+	}
+	if len(inst.Shifts) > 0 {
+		eff.SuspicionAvoidance.Shifts = inst.Shifts
+	}
+
+	prefix := fmt.Sprintf("swarm.instances[%d].", i)
+	if err := eff.validate(prefix); err != nil {
+		return Config{}, err
+	}
+	return eff, nil
+}
+
+// mergeCompat merges an instance's Compat override onto the root's.
+// Cooldown is merged field-by-field, taking the override's value only where
+// it is set (non-zero), so overriding a single cooldown doesn't zero out the
+// rest. List fields extend or replace base's values depending on each
+// StringList's Mode.
+func mergeCompat(base, override Compat) Compat {
+	merged := override
+	merged.PostmemeOpts = mergeStringList(base.PostmemeOpts, override.PostmemeOpts)
+	merged.AllowedSearches = mergeStringList(base.AllowedSearches, override.AllowedSearches)
+	merged.AutoSell = mergeStringList(base.AutoSell, override.AutoSell)
+	merged.Cooldown = mergeCooldown(base.Cooldown, override.Cooldown)
+	return merged
+}
+
+// mergeCooldown merges an instance's Cooldown override onto the root's,
+// field by field: a zero value in override means "not set for this
+// instance" and falls back to base.
+func mergeCooldown(base, override Cooldown) Cooldown {
+	merged := base
+	if override.Beg != 0 {
+		merged.Beg = override.Beg
+	}
+	if override.Fish != 0 {
+		merged.Fish = override.Fish
+	}
+	if override.Hunt != 0 {
+		merged.Hunt = override.Hunt
+	}
+	if override.Postmeme != 0 {
+		merged.Postmeme = override.Postmeme
+	}
+	if override.Search != 0 {
+		merged.Search = override.Search
+	}
+	if override.Highlow != 0 {
+		merged.Highlow = override.Highlow
+	}
+	if override.Margin != 0 {
+		merged.Margin = override.Margin
+	}
+	return merged
+}
+
+// mergeStringList merges an instance's StringList override onto the root's.
+// A Mode of ListOverride always wins, including with an explicit empty list
+// (e.g. to disable auto_sell for one instance). Otherwise, a set but empty
+// override is treated as "not set" and base is kept; a non-empty override
+// extends base.
+func mergeStringList(base, override StringList) StringList {
+	if override.Mode == ListOverride {
+		return StringList{Values: override.Values}
+	}
+	if len(override.Values) == 0 {
+		return base
+	}
+	values := make([]string, 0, len(base.Values)+len(override.Values))
+	values = append(values, base.Values...)
+	values = append(values, override.Values...)
+	return StringList{Values: values}
+}
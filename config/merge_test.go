@@ -0,0 +1,174 @@
+// Copyright (C) 2021 The Dank Grinder authors.
+//
+// This source code has been released under the GNU Affero General Public
+// License v3.0. A copy of this license is available at
+// https://www.gnu.org/licenses/agpl-3.0.en.html
+
+package config
+
+import "testing"
+
+// baseConfig returns a Config that passes Validate on its own, so tests can
+// focus on what EffectiveFor changes for a given instance.
+func baseConfig() Config {
+	return Config{
+		Token:     "root-token",
+		ChannelID: "root-channel",
+		Features: Features{
+			BalanceCheck: true,
+		},
+		Compat: Compat{
+			PostmemeOpts:    StringList{Values: []string{"a", "b"}},
+			AllowedSearches: StringList{Values: []string{"park"}},
+			AutoSell:        StringList{Values: []string{"common", "uncommon"}},
+			Cooldown: Cooldown{
+				Beg:      1,
+				Fish:     2,
+				Hunt:     3,
+				Postmeme: 4,
+				Search:   5,
+				Highlow:  6,
+				Margin:   0,
+			},
+		},
+		SuspicionAvoidance: SuspicionAvoidance{
+			Shifts: []Shift{{State: ShiftStateActive, Duration: Duration{Base: 60}}},
+		},
+	}
+}
+
+func TestEffectiveForInheritsWhenInstanceLeavesSectionsNil(t *testing.T) {
+	c := baseConfig()
+	c.Swarm.Instances = []Instance{
+		{Token: "inst-token", ChannelID: "inst-channel"},
+	}
+
+	eff, err := c.EffectiveFor(0)
+	if err != nil {
+		t.Fatalf("EffectiveFor() = %v, want nil", err)
+	}
+	if eff.Token != "inst-token" || eff.ChannelID != "inst-channel" {
+		t.Errorf("Token/ChannelID = %q/%q, want inst-token/inst-channel", eff.Token, eff.ChannelID)
+	}
+	if eff.Features != c.Features {
+		t.Errorf("Features = %+v, want inherited %+v", eff.Features, c.Features)
+	}
+	if eff.Compat.Cooldown != c.Compat.Cooldown {
+		t.Errorf("Compat.Cooldown = %+v, want inherited %+v", eff.Compat.Cooldown, c.Compat.Cooldown)
+	}
+	if len(eff.SuspicionAvoidance.Shifts) != 1 {
+		t.Errorf("SuspicionAvoidance.Shifts = %+v, want inherited from root", eff.SuspicionAvoidance.Shifts)
+	}
+}
+
+func TestEffectiveForReplacesFeaturesAndSuspicionAvoidanceWholesale(t *testing.T) {
+	c := baseConfig()
+	overrideFeatures := Features{Debug: true}
+	overrideSA := SuspicionAvoidance{
+		Shifts: []Shift{{State: ShiftStateDormant, Duration: Duration{Base: 30}}},
+	}
+	c.Swarm.Instances = []Instance{
+		{
+			Token:              "inst-token",
+			ChannelID:          "inst-channel",
+			Features:           &overrideFeatures,
+			SuspicionAvoidance: &overrideSA,
+		},
+	}
+
+	eff, err := c.EffectiveFor(0)
+	if err != nil {
+		t.Fatalf("EffectiveFor() = %v, want nil", err)
+	}
+	if eff.Features != overrideFeatures {
+		t.Errorf("Features = %+v, want the instance's override %+v wholesale", eff.Features, overrideFeatures)
+	}
+	if eff.SuspicionAvoidance.Shifts[0].State != ShiftStateDormant {
+		t.Errorf("SuspicionAvoidance.Shifts = %+v, want the instance's override wholesale", eff.SuspicionAvoidance.Shifts)
+	}
+}
+
+func TestMergeCooldownIsPerField(t *testing.T) {
+	base := baseConfig().Compat.Cooldown
+	override := Cooldown{Beg: 99}
+
+	got := mergeCooldown(base, override)
+
+	if got.Beg != 99 {
+		t.Errorf("Beg = %d, want 99 (override)", got.Beg)
+	}
+	if got.Fish != base.Fish || got.Hunt != base.Hunt || got.Postmeme != base.Postmeme ||
+		got.Search != base.Search || got.Highlow != base.Highlow {
+		t.Errorf("other fields = %+v, want inherited from base %+v", got, base)
+	}
+}
+
+func TestEffectiveForPartialCooldownOverrideStaysValid(t *testing.T) {
+	c := baseConfig()
+	overrideCompat := Compat{Cooldown: Cooldown{Beg: 99}}
+	c.Swarm.Instances = []Instance{
+		{Token: "inst-token", ChannelID: "inst-channel", Compat: &overrideCompat},
+	}
+
+	eff, err := c.EffectiveFor(0)
+	if err != nil {
+		t.Fatalf("EffectiveFor() = %v, want nil (a partial cooldown override must not zero out the rest)", err)
+	}
+	if eff.Compat.Cooldown.Beg != 99 {
+		t.Errorf("Cooldown.Beg = %d, want 99", eff.Compat.Cooldown.Beg)
+	}
+	if eff.Compat.Cooldown.Postmeme != c.Compat.Cooldown.Postmeme {
+		t.Errorf("Cooldown.Postmeme = %d, want inherited %d", eff.Compat.Cooldown.Postmeme, c.Compat.Cooldown.Postmeme)
+	}
+}
+
+func TestMergeStringListExtend(t *testing.T) {
+	base := StringList{Values: []string{"a", "b"}}
+	override := StringList{Values: []string{"c"}, Mode: ListExtend}
+
+	got := mergeStringList(base, override)
+
+	want := []string{"a", "b", "c"}
+	if len(got.Values) != len(want) {
+		t.Fatalf("Values = %v, want %v", got.Values, want)
+	}
+	for i, v := range want {
+		if got.Values[i] != v {
+			t.Errorf("Values[%d] = %q, want %q", i, got.Values[i], v)
+		}
+	}
+}
+
+func TestMergeStringListOverride(t *testing.T) {
+	base := StringList{Values: []string{"a", "b"}}
+	override := StringList{Values: []string{"c"}, Mode: ListOverride}
+
+	got := mergeStringList(base, override)
+
+	want := []string{"c"}
+	if len(got.Values) != len(want) || got.Values[0] != want[0] {
+		t.Errorf("Values = %v, want %v", got.Values, want)
+	}
+}
+
+func TestMergeStringListExplicitEmptyOverrideWins(t *testing.T) {
+	base := StringList{Values: []string{"common", "uncommon"}}
+	override := StringList{Values: nil, Mode: ListOverride}
+
+	got := mergeStringList(base, override)
+
+	if len(got.Values) != 0 {
+		t.Errorf("Values = %v, want empty (an explicit !override empty list must disable, not inherit)", got.Values)
+	}
+}
+
+func TestMergeStringListUnsetInheritsBase(t *testing.T) {
+	base := StringList{Values: []string{"a", "b"}}
+	override := StringList{} // not set at all: zero Mode (ListExtend), no values
+
+	got := mergeStringList(base, override)
+
+	if len(got.Values) != len(base.Values) {
+		t.Errorf("Values = %v, want inherited base %v", got.Values, base.Values)
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright (C) 2021 The Dank Grinder authors.
+//
+// This source code has been released under the GNU Affero General Public
+// License v3.0. A copy of this license is available at
+// https://www.gnu.org/licenses/agpl-3.0.en.html
+
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// LoadWithOverrides loads Config the same way Load does and then layers
+// overrides on top, in order of increasing precedence: config.yml ->
+// environment variables (DANK_*, see ApplyEnv) -> CLI flags parsed from args
+// (see ApplyFlags).
+func LoadWithOverrides(dir string, args []string) (Config, error) {
+	cfg, err := Load(dir)
+	if err != nil {
+		return Config{}, err
+	}
+	if err := cfg.ApplyEnv("DANK"); err != nil {
+		return Config{}, fmt.Errorf("error while applying environment overrides: %v", err)
+	}
+	if err := cfg.ApplyFlags(args); err != nil {
+		return Config{}, fmt.Errorf("error while applying flag overrides: %v", err)
+	}
+	return cfg, nil
+}
+
+// ApplyEnv overrides c's fields from environment variables named
+// "<prefix>_<SECTION>_<FIELD>", derived from the yaml tags of nested structs,
+// e.g. DANK_TOKEN, DANK_CHANNEL_ID or DANK_COMPAT_COOLDOWN_BEG. Fields whose
+// variable is unset are left untouched. Comma-separated values are accepted
+// for list fields such as compatibility.postmeme_options.
+func (c *Config) ApplyEnv(prefix string) error {
+	return walkFields(reflect.ValueOf(c).Elem(), nil, func(path []string, fv reflect.Value) error {
+		name := prefix + "_" + strings.ToUpper(strings.Join(overrideName(path), "_"))
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			return nil
+		}
+		if err := setField(fv, raw); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		return nil
+	})
+}
+
+// ApplyFlags overrides c's fields from CLI flags named "-<section>-<field>"
+// (dashed, lowercase), mirroring the variable names ApplyEnv derives. Flags
+// that are not passed in args are left untouched.
+func (c *Config) ApplyFlags(args []string) error {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	fields := make(map[string]reflect.Value)
+	raw := make(map[string]*string)
+
+	err := walkFields(reflect.ValueOf(c).Elem(), nil, func(path []string, fv reflect.Value) error {
+		name := strings.ToLower(strings.Join(overrideName(path), "-"))
+		fields[name] = fv
+		raw[name] = fs.String(name, "", fmt.Sprintf("override %s", strings.Join(path, ".")))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var setErr error
+	fs.Visit(func(f *flag.Flag) {
+		if setErr != nil {
+			return
+		}
+		fv, ok := fields[f.Name]
+		if !ok {
+			return
+		}
+		if err := setField(fv, *raw[f.Name]); err != nil {
+			setErr = fmt.Errorf("-%s: %v", f.Name, err)
+		}
+	})
+	return setErr
+}
+
+// overrideSegmentAliases renames path segments that are shorter or more
+// conventional in an env var or flag name than their yaml tag, most notably
+// "compatibility" -> "compat" (DANK_COMPAT_COOLDOWN_BEG, -compat-cooldown-beg).
+var overrideSegmentAliases = map[string]string{
+	"compatibility": "compat",
+}
+
+// overrideName maps a field path from its yaml tag names to the names used
+// in env vars and CLI flags, applying overrideSegmentAliases.
+func overrideName(path []string) []string {
+	out := make([]string, len(path))
+	for i, segment := range path {
+		if alias, ok := overrideSegmentAliases[segment]; ok {
+			segment = alias
+		}
+		out[i] = segment
+	}
+	return out
+}
+
+// walkFields calls fn for every leaf field reachable from v (a struct),
+// passing the path of yaml tag names from v down to that field. Slices
+// (shifts, swarm instances) and pointers (instance-level overrides) are not
+// addressable as a single env var or flag and are skipped.
+func walkFields(v reflect.Value, path []string, fn func(path []string, field reflect.Value) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(sf.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		fieldPath := append(append([]string{}, path...), name)
+
+		switch {
+		case fv.Type() == reflect.TypeOf(StringList{}):
+			if err := fn(fieldPath, fv); err != nil {
+				return err
+			}
+		case fv.Kind() == reflect.Struct:
+			if err := walkFields(fv, fieldPath, fn); err != nil {
+				return err
+			}
+		case fv.Kind() == reflect.String, fv.Kind() == reflect.Bool, fv.Kind() == reflect.Int:
+			if err := fn(fieldPath, fv); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// setField coerces raw into fv's type and assigns it. fv must be one of the
+// kinds walkFields yields: string, bool, int or StringList.
+func setField(fv reflect.Value, raw string) error {
+	if fv.Type() == reflect.TypeOf(StringList{}) {
+		var values []string
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		fv.Set(reflect.ValueOf(StringList{Values: values, Mode: ListOverride}))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid bool value %q", raw)
+		}
+		fv.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid int value %q", raw)
+		}
+		fv.SetInt(int64(n))
+	default:
+		return fmt.Errorf("unsupported field kind %v", fv.Kind())
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+// Copyright (C) 2021 The Dank Grinder authors.
+//
+// This source code has been released under the GNU Affero General Public
+// License v3.0. A copy of this license is available at
+// https://www.gnu.org/licenses/agpl-3.0.en.html
+
+package config
+
+import "testing"
+
+func TestApplyEnv(t *testing.T) {
+	t.Setenv("DANK_TOKEN", "env-token")
+	t.Setenv("DANK_COMPAT_COOLDOWN_BEG", "42")
+	t.Setenv("DANK_COMPAT_POSTMEME_OPTIONS", "a, b ,c")
+
+	var cfg Config
+	cfg.Compat.Cooldown.Beg = 1
+	if err := cfg.ApplyEnv("DANK"); err != nil {
+		t.Fatalf("ApplyEnv() = %v, want nil", err)
+	}
+
+	if cfg.Token != "env-token" {
+		t.Errorf("Token = %q, want %q", cfg.Token, "env-token")
+	}
+	if cfg.Compat.Cooldown.Beg != 42 {
+		t.Errorf("Compat.Cooldown.Beg = %d, want 42 (DANK_COMPAT_COOLDOWN_BEG was not applied)", cfg.Compat.Cooldown.Beg)
+	}
+	want := []string{"a", "b", "c"}
+	if len(cfg.Compat.PostmemeOpts.Values) != len(want) {
+		t.Fatalf("Compat.PostmemeOpts.Values = %v, want %v", cfg.Compat.PostmemeOpts.Values, want)
+	}
+	for i, v := range want {
+		if cfg.Compat.PostmemeOpts.Values[i] != v {
+			t.Errorf("Compat.PostmemeOpts.Values[%d] = %q, want %q", i, cfg.Compat.PostmemeOpts.Values[i], v)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright (C) 2021 The Dank Grinder authors.
+//
+// This source code has been released under the GNU Affero General Public
+// License v3.0. A copy of this license is available at
+// https://www.gnu.org/licenses/agpl-3.0.en.html
+
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// wallClockWindow is the parsed form of a Shift's At field.
+type wallClockWindow struct {
+	begHour, begMin int
+	endHour, endMin int
+	days            map[time.Weekday]bool // empty means every day
+}
+
+// parseAt parses an "HH:MM-HH:MM" window.
+func parseAt(at string) (wallClockWindow, error) {
+	parts := strings.SplitN(at, "-", 2)
+	if len(parts) != 2 {
+		return wallClockWindow{}, fmt.Errorf("invalid at window %q, expected format HH:MM-HH:MM", at)
+	}
+	begHour, begMin, err := parseClock(parts[0])
+	if err != nil {
+		return wallClockWindow{}, fmt.Errorf("invalid at window %q: %v", at, err)
+	}
+	endHour, endMin, err := parseClock(parts[1])
+	if err != nil {
+		return wallClockWindow{}, fmt.Errorf("invalid at window %q: %v", at, err)
+	}
+	return wallClockWindow{
+		begHour: begHour,
+		begMin:  begMin,
+		endHour: endHour,
+		endMin:  endMin,
+	}, nil
+}
+
+func parseClock(s string) (hour, min int, err error) {
+	if _, err := fmt.Sscanf(s, "%d:%d", &hour, &min); err != nil {
+		return 0, 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	if hour < 0 || hour > 23 || min < 0 || min > 59 {
+		return 0, 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return hour, min, nil
+}
+
+func parseDays(days []string) (map[time.Weekday]bool, error) {
+	if len(days) == 0 {
+		return nil, nil
+	}
+	set := make(map[time.Weekday]bool, len(days))
+	for _, d := range days {
+		wd, ok := weekdays[strings.ToLower(d)]
+		if !ok {
+			return nil, fmt.Errorf("invalid day %q, expected one of mon, tue, wed, thu, fri, sat, sun", d)
+		}
+		set[wd] = true
+	}
+	return set, nil
+}
+
+// minutesOfDay returns the number of minutes since 00:00, wrapping windows
+// that cross midnight are represented with end < beg.
+func (w wallClockWindow) minutesOfDay() (beg, end int) {
+	return w.begHour*60 + w.begMin, w.endHour*60 + w.endMin
+}
+
+// contains reports whether t, interpreted in t's own location, falls inside
+// the window.
+func (w wallClockWindow) contains(t time.Time) bool {
+	if w.days != nil && !w.days[t.Weekday()] {
+		return false
+	}
+	beg, end := w.minutesOfDay()
+	cur := t.Hour()*60 + t.Minute()
+	if beg == end {
+		return true // a full-day window
+	}
+	if beg < end {
+		return cur >= beg && cur < end
+	}
+	// Window crosses midnight, e.g. 22:00-06:00.
+	return cur >= beg || cur < end
+}
+
+// validateShifts checks that the timezone is known, that no shift mixes the
+// duration and wall-clock forms, and that no two wall-clock windows overlap.
+func validateShifts(shifts []Shift, timezone string) error {
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return fmt.Errorf("timezone: %v", err)
+	}
+
+	type parsed struct {
+		window wallClockWindow
+		state  string
+		index  int // position in shifts
+	}
+	var windows []parsed
+	for i, shift := range shifts {
+		hasDuration := shift.Duration.Base != 0 || shift.Duration.Variance != 0
+		if shift.isWallClock() {
+			if hasDuration {
+				return fmt.Errorf("shifts[%d]: cannot mix \"at\" and \"duration\" on the same shift", i)
+			}
+			window, err := parseAt(shift.At)
+			if err != nil {
+				return fmt.Errorf("shifts[%d]: %v", i, err)
+			}
+			days, err := parseDays(shift.Days)
+			if err != nil {
+				return fmt.Errorf("shifts[%d]: %v", i, err)
+			}
+			window.days = days
+			windows = append(windows, parsed{window: window, state: shift.State, index: i})
+			continue
+		}
+		if len(shift.Days) > 0 {
+			return fmt.Errorf("shifts[%d]: \"days\" is only valid alongside \"at\"", i)
+		}
+	}
+
+	for i := 0; i < len(windows); i++ {
+		for j := i + 1; j < len(windows); j++ {
+			if windows[i].window.overlaps(windows[j].window) {
+				return fmt.Errorf("overlapping shift windows at indexes %d and %d", windows[i].index, windows[j].index)
+			}
+		}
+	}
+	return nil
+}
+
+// minuteInterval is a [beg,end) range of minutes since 00:00.
+type minuteInterval struct {
+	beg, end int
+}
+
+// minuteIntervals splits a window's minutesOfDay into one interval, or two
+// if it crosses midnight, so intersection can be tested as plain ranges.
+func (w wallClockWindow) minuteIntervals() []minuteInterval {
+	beg, end := w.minutesOfDay()
+	if beg == end {
+		return []minuteInterval{{beg: 0, end: 1440}} // a full-day window
+	}
+	if beg < end {
+		return []minuteInterval{{beg: beg, end: end}}
+	}
+	// Window crosses midnight, e.g. 22:00-06:00: split into [beg,1440) and
+	// [0,end).
+	return []minuteInterval{{beg: beg, end: 1440}, {beg: 0, end: end}}
+}
+
+func (a minuteInterval) intersects(b minuteInterval) bool {
+	return a.beg < b.end && b.beg < a.end
+}
+
+// overlaps reports whether two wall-clock windows can ever be active at the
+// same time, taking into account the days they apply to.
+func (w wallClockWindow) overlaps(other wallClockWindow) bool {
+	if w.days != nil && other.days != nil {
+		shared := false
+		for d := range w.days {
+			if other.days[d] {
+				shared = true
+				break
+			}
+		}
+		if !shared {
+			return false
+		}
+	}
+	for _, a := range w.minuteIntervals() {
+		for _, b := range other.minuteIntervals() {
+			if a.intersects(b) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CurrentShift returns the shift that is active at the given time, according
+// to the wall-clock windows configured on this SuspicionAvoidance. The
+// second return value is false if no wall-clock shift applies, in which case
+// callers should fall back to the duration-based cycle.
+func (s SuspicionAvoidance) CurrentShift(now time.Time) (Shift, bool, error) {
+	timezone := s.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return Shift{}, false, fmt.Errorf("timezone: %v", err)
+	}
+	local := now.In(loc)
+
+	for _, shift := range s.Shifts {
+		if !shift.isWallClock() {
+			continue
+		}
+		window, err := parseAt(shift.At)
+		if err != nil {
+			return Shift{}, false, err
+		}
+		days, err := parseDays(shift.Days)
+		if err != nil {
+			return Shift{}, false, err
+		}
+		window.days = days
+		if window.contains(local) {
+			return shift, true, nil
+		}
+	}
+	return Shift{}, false, nil
+}
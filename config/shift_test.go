@@ -0,0 +1,125 @@
+// Copyright (C) 2021 The Dank Grinder authors.
+//
+// This source code has been released under the GNU Affero General Public
+// License v3.0. A copy of this license is available at
+// https://www.gnu.org/licenses/agpl-3.0.en.html
+
+package config
+
+import "testing"
+
+func TestWallClockWindowOverlaps(t *testing.T) {
+	tests := []struct {
+		name        string
+		a, b        string
+		wantOverlap bool
+	}{
+		{
+			name:        "night shift does not overlap day shift",
+			a:           "22:00-06:00",
+			b:           "10:00-11:00",
+			wantOverlap: false,
+		},
+		{
+			name:        "adjacent windows do not overlap",
+			a:           "09:00-17:00",
+			b:           "17:00-18:00",
+			wantOverlap: false,
+		},
+		{
+			name:        "overlapping same-day windows",
+			a:           "09:00-17:00",
+			b:           "16:00-18:00",
+			wantOverlap: true,
+		},
+		{
+			name:        "midnight-crossing windows overlapping near midnight",
+			a:           "22:00-02:00",
+			b:           "01:00-05:00",
+			wantOverlap: true,
+		},
+		{
+			name:        "late-evening and early-morning windows that don't overlap",
+			a:           "22:00-23:00",
+			b:           "01:00-05:00",
+			wantOverlap: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wa, err := parseAt(tt.a)
+			if err != nil {
+				t.Fatalf("parseAt(%q): %v", tt.a, err)
+			}
+			wb, err := parseAt(tt.b)
+			if err != nil {
+				t.Fatalf("parseAt(%q): %v", tt.b, err)
+			}
+			if got := wa.overlaps(wb); got != tt.wantOverlap {
+				t.Errorf("%q.overlaps(%q) = %v, want %v", tt.a, tt.b, got, tt.wantOverlap)
+			}
+			if got := wb.overlaps(wa); got != tt.wantOverlap {
+				t.Errorf("%q.overlaps(%q) = %v, want %v (not symmetric)", tt.b, tt.a, got, tt.wantOverlap)
+			}
+		})
+	}
+}
+
+func TestWallClockWindowOverlapsRespectsDays(t *testing.T) {
+	a, err := parseAt("09:00-17:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := parseAt("09:00-17:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	aDays, err := parseDays([]string{"mon", "tue"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bDays, err := parseDays([]string{"wed", "thu"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.days, b.days = aDays, bDays
+
+	if a.overlaps(b) {
+		t.Error("windows on disjoint days should not overlap even though the clock ranges are identical")
+	}
+}
+
+func TestValidateShiftsAllowsNightAndDayShift(t *testing.T) {
+	shifts := []Shift{
+		{State: ShiftStateActive, At: "22:00-06:00"},
+		{State: ShiftStateDormant, At: "10:00-11:00"},
+	}
+	if err := validateShifts(shifts, "UTC"); err != nil {
+		t.Errorf("validateShifts() = %v, want nil", err)
+	}
+}
+
+func TestValidateShiftsRejectsOverlap(t *testing.T) {
+	shifts := []Shift{
+		{State: ShiftStateActive, At: "09:00-17:00"},
+		{State: ShiftStateDormant, At: "16:00-18:00"},
+	}
+	if err := validateShifts(shifts, "UTC"); err == nil {
+		t.Error("validateShifts() = nil, want an overlap error")
+	}
+}
+
+func TestValidateShiftsRejectsMixedForms(t *testing.T) {
+	shifts := []Shift{
+		{State: ShiftStateActive, At: "09:00-17:00", Duration: Duration{Base: 10}},
+	}
+	if err := validateShifts(shifts, "UTC"); err == nil {
+		t.Error("validateShifts() = nil, want a mixed-form error")
+	}
+}
+
+func TestValidateShiftsRejectsUnknownTimezone(t *testing.T) {
+	if err := validateShifts(nil, "Mars/Olympus_Mons"); err == nil {
+		t.Error("validateShifts() = nil, want an unknown timezone error")
+	}
+}
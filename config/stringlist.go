@@ -0,0 +1,54 @@
+// Copyright (C) 2021 The Dank Grinder authors.
+//
+// This source code has been released under the GNU Affero General Public
+// License v3.0. A copy of this license is available at
+// https://www.gnu.org/licenses/agpl-3.0.en.html
+
+package config
+
+import "gopkg.in/yaml.v3"
+
+// ListMode controls how an instance-level StringList combines with the root
+// value it is merged into by EffectiveFor.
+type ListMode int
+
+const (
+	// ListExtend appends the instance's values to the root's. It is the
+	// default when a list carries no explicit tag.
+	ListExtend ListMode = iota
+	// ListOverride replaces the root's values entirely.
+	ListOverride
+)
+
+// StringList is a []string that also remembers whether it was tagged
+// "!override" or "!extend" in YAML, e.g.:
+//
+//	postmeme_options: !override
+//	  - a
+//	  - b
+//
+// The tag only matters for lists set on a Swarm Instance; root-level lists
+// always act as the base and ignore Mode.
+type StringList struct {
+	Values []string
+	Mode   ListMode
+}
+
+func (l *StringList) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Tag {
+	case "!override":
+		l.Mode = ListOverride
+	default:
+		l.Mode = ListExtend
+	}
+	var values []string
+	if err := value.Decode(&values); err != nil {
+		return err
+	}
+	l.Values = values
+	return nil
+}
+
+func (l StringList) MarshalYAML() (interface{}, error) {
+	return l.Values, nil
+}
@@ -0,0 +1,181 @@
+// Copyright (C) 2021 The Dank Grinder authors.
+//
+// This source code has been released under the GNU Affero General Public
+// License v3.0. A copy of this license is available at
+// https://www.gnu.org/licenses/agpl-3.0.en.html
+
+package config
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDebounce coalesces the burst of write events an editor typically
+// produces for a single save.
+const reloadDebounce = 250 * time.Millisecond
+
+// Watcher loads a config.yml and keeps it up to date by watching the file
+// for changes, revalidating on every change and atomically publishing the
+// new Config to subscribers. If a change fails to load or validate, or
+// touches a field that requires a restart, the last-good config is kept and
+// the error is logged.
+type Watcher struct {
+	dir  string
+	fsw  *fsnotify.Watcher
+	done chan struct{}
+	once sync.Once
+
+	mu      sync.RWMutex
+	current Config
+	subs    []chan Config
+}
+
+// NewWatcher loads dir/config.yml and starts watching it for changes.
+func NewWatcher(dir string) (*Watcher, error) {
+	cfg, err := Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %v", err)
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("error while creating file watcher: %v", err)
+	}
+	if err := fsw.Add(dir); err != nil {
+		fsw.Close()
+		return nil, fmt.Errorf("error while watching %v: %v", dir, err)
+	}
+
+	w := &Watcher{
+		dir:     dir,
+		fsw:     fsw,
+		current: cfg,
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// Current returns the most recently applied config.
+func (w *Watcher) Current() Config {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current
+}
+
+// Subscribe returns a channel that receives every config applied from now
+// on. The channel is buffered by 1; a subscriber that falls behind only ever
+// sees the latest config, never a backlog.
+func (w *Watcher) Subscribe() <-chan Config {
+	ch := make(chan Config, 1)
+	w.mu.Lock()
+	w.subs = append(w.subs, ch)
+	w.mu.Unlock()
+	return ch
+}
+
+// Close stops watching the config file. It is safe to call more than once.
+func (w *Watcher) Close() error {
+	w.once.Do(func() { close(w.done) })
+	return w.fsw.Close()
+}
+
+func (w *Watcher) run() {
+	var timer *time.Timer
+	for {
+		select {
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			if filepath.Base(event.Name) != "config.yml" {
+				continue
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.AfterFunc(reloadDebounce, w.reload)
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("config watcher: %v", err)
+		case <-w.done:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		}
+	}
+}
+
+func (w *Watcher) reload() {
+	next, err := Load(w.dir)
+	if err != nil {
+		log.Printf("config watcher: error while loading config: %v, keeping last-good config", err)
+		return
+	}
+	if err := next.Validate(); err != nil {
+		log.Printf("config watcher: invalid config: %v, keeping last-good config", err)
+		return
+	}
+
+	w.mu.Lock()
+	if err := restartRequiredBy(w.current, next); err != nil && !next.Features.AllowUnsafeReload {
+		w.mu.Unlock()
+		log.Printf("config watcher: %v, keeping last-good config (set features.allow_unsafe_reload to apply anyway)", err)
+		return
+	}
+	w.current = next
+	subs := make([]chan Config, len(w.subs))
+	copy(subs, w.subs)
+	w.mu.Unlock()
+
+	for _, ch := range subs {
+		select {
+		case ch <- next:
+		default:
+			// Drop the stale pending value so the subscriber sees the
+			// latest config instead of blocking the watcher.
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- next
+		}
+	}
+}
+
+// restartRequiredBy reports whether next changes a field that the running
+// process cannot safely pick up without a restart: the token, channel ID or
+// swarm instance topology (count, tokens or channel IDs).
+func restartRequiredBy(prev, next Config) error {
+	if prev.Token != next.Token {
+		return fmt.Errorf("token: changing this requires a restart")
+	}
+	if prev.ChannelID != next.ChannelID {
+		return fmt.Errorf("channel_id: changing this requires a restart")
+	}
+	if len(prev.Swarm.Instances) != len(next.Swarm.Instances) {
+		return fmt.Errorf("swarm.instances: adding or removing an instance requires a restart")
+	}
+	for i := range prev.Swarm.Instances {
+		p, n := prev.Swarm.Instances[i], next.Swarm.Instances[i]
+		if p.Token != n.Token || p.ChannelID != n.ChannelID {
+			return fmt.Errorf("swarm.instances[%d]: changing token or channel_id requires a restart", i)
+		}
+	}
+	return nil
+}
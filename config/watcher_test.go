@@ -0,0 +1,170 @@
+// Copyright (C) 2021 The Dank Grinder authors.
+//
+// This source code has been released under the GNU Affero General Public
+// License v3.0. A copy of this license is available at
+// https://www.gnu.org/licenses/agpl-3.0.en.html
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const validConfigYAML = `
+token: root-token
+channel_id: root-channel
+compatibility:
+  postmeme_options: [a]
+  allowed_searches: [park]
+  auto_sell: [common]
+  cooldown:
+    beg: 1
+    fish: 2
+    hunt: 3
+    postmeme: 4
+    search: 5
+    highlow: 6
+suspicion_avoidance:
+  shifts:
+    - state: active
+      duration:
+        base: 60
+`
+
+func writeConfig(t *testing.T, dir, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, "config.yml"), []byte(content), 0o644); err != nil {
+		t.Fatalf("error while writing config.yml: %v", err)
+	}
+}
+
+// waitUntil polls cond every 20ms until it returns true or timeout elapses,
+// to account for the watcher's debounce plus fsnotify event latency.
+func waitUntil(t *testing.T, timeout time.Duration, cond func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return cond()
+}
+
+func TestWatcherRollsBackOnInvalidReload(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, validConfigYAML)
+
+	w, err := NewWatcher(dir)
+	if err != nil {
+		t.Fatalf("NewWatcher() = %v, want nil", err)
+	}
+	defer w.Close()
+
+	if w.Current().Token != "root-token" {
+		t.Fatalf("Current().Token = %q, want root-token", w.Current().Token)
+	}
+
+	writeConfig(t, dir, "token: \"\"\nchannel_id: root-channel\n")
+
+	// Give the debounced reload a chance to run and fail validation.
+	time.Sleep(reloadDebounce + 250*time.Millisecond)
+
+	if got := w.Current().Token; got != "root-token" {
+		t.Errorf("Current().Token = %q after invalid reload, want root-token (last-good config)", got)
+	}
+}
+
+func TestWatcherRejectsTokenChangeWithoutAllowUnsafeReload(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, validConfigYAML)
+
+	w, err := NewWatcher(dir)
+	if err != nil {
+		t.Fatalf("NewWatcher() = %v, want nil", err)
+	}
+	defer w.Close()
+
+	const otherTokenYAML = `
+token: other-token
+channel_id: root-channel
+compatibility:
+  postmeme_options: [a]
+  allowed_searches: [park]
+  auto_sell: [common]
+  cooldown:
+    beg: 1
+    fish: 2
+    hunt: 3
+    postmeme: 4
+    search: 5
+    highlow: 6
+suspicion_avoidance:
+  shifts:
+    - state: active
+      duration:
+        base: 60
+`
+	writeConfig(t, dir, otherTokenYAML)
+
+	time.Sleep(reloadDebounce + 250*time.Millisecond)
+
+	if got := w.Current().Token; got != "root-token" {
+		t.Errorf("Current().Token = %q, want root-token (a token change must require AllowUnsafeReload)", got)
+	}
+}
+
+func TestWatcherHotAppliesSafeChangeAndNotifiesSubscribers(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, validConfigYAML)
+
+	w, err := NewWatcher(dir)
+	if err != nil {
+		t.Fatalf("NewWatcher() = %v, want nil", err)
+	}
+	defer w.Close()
+
+	sub := w.Subscribe()
+
+	const changedCooldown = `
+token: root-token
+channel_id: root-channel
+compatibility:
+  postmeme_options: [a]
+  allowed_searches: [park]
+  auto_sell: [common]
+  cooldown:
+    beg: 99
+    fish: 2
+    hunt: 3
+    postmeme: 4
+    search: 5
+    highlow: 6
+suspicion_avoidance:
+  shifts:
+    - state: active
+      duration:
+        base: 60
+`
+	writeConfig(t, dir, changedCooldown)
+
+	ok := waitUntil(t, 2*time.Second, func() bool {
+		return w.Current().Compat.Cooldown.Beg == 99
+	})
+	if !ok {
+		t.Fatalf("Current().Compat.Cooldown.Beg = %d, want 99 (a safe field should hot-apply)", w.Current().Compat.Cooldown.Beg)
+	}
+
+	select {
+	case next := <-sub:
+		if next.Compat.Cooldown.Beg != 99 {
+			t.Errorf("subscriber received Compat.Cooldown.Beg = %d, want 99", next.Compat.Cooldown.Beg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("subscriber never received the reloaded config")
+	}
+}